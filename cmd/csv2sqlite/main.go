@@ -0,0 +1,226 @@
+// Command csv2sqlite imports one or more delimited text files into a sqlite
+// database.
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/psanford/csv2sqlite"
+)
+
+var (
+	db            = flag.String("db", "csv.db", "Database file")
+	tableName     = flag.String("table", "csv", "Table name")
+	createColumns = flag.Bool("create-columns", true, "Create any missing columns in table")
+	trunc         = flag.Bool("trunc", false, "Truncate table before inserting")
+	ephemeral     = flag.Bool("i", false, "Create an ephemeral db and start an interactive session")
+	virtualF      = flag.Bool("virtual", false, "Register input files as virtual tables instead of importing them, then start an interactive session")
+	separatorStr  = flag.String("separator", ",", "Record separator")
+	headerF       = flag.String("header", "", "Comma seperated header to use (files will be assumed to have no header")
+	sampleRows    = flag.Int("sample-rows", 1000, "Number of rows to sample when inferring column types (0 disables inference)")
+	schemaF       = flag.String("schema", "", "Comma separated col:type overrides for inferred column types, e.g. id:integer,amount:real")
+	batchSize     = flag.Int("batch-size", 10000, "Number of rows to insert per transaction")
+	fast          = flag.Bool("fast", false, "Trade crash-safety for bulk-load speed (PRAGMA synchronous=OFF, larger cache_size)")
+	quiet         = flag.Bool("quiet", false, "Suppress progress output")
+	formatF       = flag.String("format", "", "Force the input parser (csv, tsv, jsonl, xlsx); default: detect from file extension")
+
+	lazyQuotes       = flag.Bool("lazy-quotes", false, "Allow bare double quotes in unquoted fields")
+	fieldsPerRecord  = flag.Int("fields-per-record", -1, "Expected fields per row; -1 allows ragged rows, 0 requires the header's width")
+	commentF         = flag.String("comment", "", "Skip lines beginning with this character")
+	trimLeadingSpace = flag.Bool("trim-leading-space", false, "Trim leading whitespace from fields")
+	encodingF        = flag.String("encoding", "", "Source text encoding: utf-8 (default), gbk, latin1, utf-16")
+	onErrorF         = flag.String("on-error", "fail", "How to handle malformed rows: fail, skip, log")
+)
+
+func main() {
+	flag.Parse()
+
+	if len(*separatorStr) != 1 {
+		log.Fatalf("--separator must be a single character")
+	}
+
+	var separator rune
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "separator" {
+			separator = rune((*separatorStr)[0])
+		}
+	})
+
+	var format csv2sqlite.Format
+	switch *formatF {
+	case "":
+		// auto-detect per file
+	case "csv", "tsv", "jsonl", "xlsx":
+		format = csv2sqlite.Format(*formatF)
+	default:
+		log.Fatalf("--format must be one of csv, tsv, jsonl, xlsx")
+	}
+
+	schemaOverrides, err := csv2sqlite.ParseSchemaOverrides(*schemaF)
+	if err != nil {
+		log.Fatalf("--schema parse err: %s", err)
+	}
+
+	var comment rune
+	if *commentF != "" {
+		if len(*commentF) != 1 {
+			log.Fatalf("--comment must be a single character")
+		}
+		comment = rune((*commentF)[0])
+	}
+
+	var onError csv2sqlite.OnErrorMode
+	switch *onErrorF {
+	case "fail":
+		onError = csv2sqlite.OnErrorFail
+	case "skip":
+		onError = csv2sqlite.OnErrorSkip
+	case "log":
+		onError = csv2sqlite.OnErrorLog
+	default:
+		log.Fatalf("--on-error must be one of fail, skip, log")
+	}
+
+	var header []string
+	if *headerF != "" {
+		r := csv.NewReader(bytes.NewBufferString(*headerF))
+		header, err = r.Read()
+		if err != nil {
+			log.Fatalf("-header parse err: %s", err)
+		}
+	}
+
+	args := flag.Args()
+	if len(args) < 1 {
+		log.Fatalf("usage: %s <input.csv> [input2.csv...]", os.Args[0])
+	}
+
+	if *ephemeral {
+		f, err := ioutil.TempFile("", "csv2sqlite")
+		if err != nil {
+			log.Fatalf("create tmpfile err: %s", err)
+		}
+		f.Close()
+		name := f.Name()
+		defer os.Remove(name)
+		*db = name
+	}
+
+	sqlDB, err := sql.Open("sqlite", *db)
+	if err != nil {
+		log.Fatalf("open db err: %s", err)
+	}
+	defer sqlDB.Close()
+
+	if *virtualF {
+		virtualSeparator := separator
+		if virtualSeparator == 0 {
+			virtualSeparator = ','
+		}
+
+		csv2sqlite.RegisterVirtualModule(sqlDB)
+		for _, filename := range args {
+			name := tableNameForFile(filename)
+			if err := csv2sqlite.CreateVirtualTable(sqlDB, name, filename, virtualSeparator, *schemaF); err != nil {
+				log.Fatalf("register virtual table for %s err: %s", filename, err)
+			}
+			fmt.Fprintf(os.Stderr, "%s -> %s\n", filename, name)
+		}
+		if err := runREPL(sqlDB); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	imp := &csv2sqlite.Importer{
+		DB:              sqlDB,
+		TableName:       *tableName,
+		Separator:       separator,
+		Header:          header,
+		CreateColumns:   *createColumns,
+		Truncate:        *trunc,
+		BatchSize:       *batchSize,
+		Fast:            *fast,
+		Format:          format,
+		Encoding:        *encodingF,
+		TypeInference:   true,
+		SampleRows:      *sampleRows,
+		SchemaOverrides: schemaOverrides,
+
+		LazyQuotes:       *lazyQuotes,
+		FieldsPerRecord:  *fieldsPerRecord,
+		Comment:          comment,
+		TrimLeadingSpace: *trimLeadingSpace,
+		OnError:          onError,
+	}
+
+	if !*quiet {
+		imp.ProgressFn = newProgressPrinter()
+	}
+
+	ctx := context.Background()
+	for _, filename := range args {
+		if _, err := imp.ImportFile(ctx, filename); err != nil {
+			log.Fatalf("import %s err: %s", filename, err)
+		}
+		if !*quiet {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	if *ephemeral {
+		if err := runREPL(sqlDB); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+var tableNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// tableNameForFile derives a virtual table name from an input file's base
+// name, stripping its extension and replacing any character sqlite
+// wouldn't accept unquoted with an underscore.
+func tableNameForFile(path string) string {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := base[:len(base)-len(ext)]
+	name = tableNameSanitizer.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "t_" + name
+	}
+	return name
+}
+
+// newProgressPrinter returns a csv2sqlite.Importer.ProgressFn that prints
+// bytes processed, rows inserted, and throughput to stderr, throttled to a
+// few updates per second so it doesn't dominate import time itself.
+func newProgressPrinter() func(csv2sqlite.Stats) {
+	start := time.Now()
+	var last time.Time
+
+	return func(stats csv2sqlite.Stats) {
+		now := time.Now()
+		if now.Sub(last) < 200*time.Millisecond {
+			return
+		}
+		last = now
+
+		elapsed := now.Sub(start).Seconds()
+		var rate float64
+		if elapsed > 0 {
+			rate = float64(stats.BytesProcessed) / elapsed / 1024
+		}
+		fmt.Fprintf(os.Stderr, "\r%s: %d bytes, %d rows (%.1f KB/s)", stats.TableName, stats.BytesProcessed, stats.RowsInserted, rate)
+	}
+}
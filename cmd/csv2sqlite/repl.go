@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chzyer/readline"
+	"github.com/psanford/csv2sqlite"
+)
+
+// outputMode selects how repl prints query results.
+type outputMode string
+
+const (
+	outputTable outputMode = "table"
+	outputCSV   outputMode = "csv"
+	outputJSON  outputMode = "json"
+)
+
+// repl is a minimal sqlite3-CLI-alike that runs queries directly against an
+// already-open *sql.DB, so csv2sqlite's -i ephemeral mode works without
+// shelling out to the external sqlite3 binary.
+type repl struct {
+	db   *sql.DB
+	mode outputMode
+
+	out     io.Writer
+	outFile *os.File // set when .output redirects away from stdout
+}
+
+// runREPL reads queries and meta-commands from stdin until EOF or .exit.
+func runREPL(db *sql.DB) error {
+	rl, err := readline.New("sqlite> ")
+	if err != nil {
+		return fmt.Errorf("readline init err: %w", err)
+	}
+	defer rl.Close()
+
+	r := &repl{db: db, mode: outputTable, out: os.Stdout}
+	defer r.closeOutput()
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var runErr error
+		if strings.HasPrefix(line, ".") {
+			runErr = r.runMeta(line)
+		} else {
+			runErr = r.runQuery(line)
+		}
+		if runErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", runErr)
+		}
+	}
+}
+
+func (r *repl) closeOutput() {
+	if r.outFile != nil {
+		r.outFile.Close()
+		r.outFile = nil
+	}
+}
+
+// runMeta handles a ".command ..." line.
+func (r *repl) runMeta(line string) error {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case ".tables":
+		return r.printColumn("SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	case ".schema":
+		query := "SELECT sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY name"
+		if len(args) == 1 {
+			query = fmt.Sprintf("SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name = %s ORDER BY name", quoteSQLString(args[0]))
+		}
+		return r.printColumn(query, ";")
+	case ".mode":
+		if len(args) != 1 {
+			return fmt.Errorf(".mode requires one of: table, csv, json")
+		}
+		switch args[0] {
+		case "table":
+			r.mode = outputTable
+		case "csv":
+			r.mode = outputCSV
+		case "json":
+			r.mode = outputJSON
+		default:
+			return fmt.Errorf("unknown .mode %q, want table, csv, or json", args[0])
+		}
+		return nil
+	case ".output":
+		if len(args) != 1 {
+			return fmt.Errorf(".output requires a filename or \"stdout\"")
+		}
+		r.closeOutput()
+		if args[0] == "stdout" {
+			r.out = os.Stdout
+			return nil
+		}
+		f, err := os.Create(args[0])
+		if err != nil {
+			return fmt.Errorf("open output file err: %w", err)
+		}
+		r.outFile = f
+		r.out = f
+		return nil
+	case ".import":
+		if len(args) != 2 {
+			return fmt.Errorf(".import requires a file and a table name")
+		}
+		imp := &csv2sqlite.Importer{
+			DB:            r.db,
+			TableName:     args[1],
+			CreateColumns: true,
+			TypeInference: true,
+			SampleRows:    1000,
+		}
+		stats, err := imp.ImportFile(context.Background(), args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "imported %d rows into %s\n", stats.RowsInserted, args[1])
+		return nil
+	case ".exit", ".quit":
+		r.closeOutput()
+		os.Exit(0)
+		return nil
+	case ".help":
+		fmt.Fprintln(r.out, ".tables                 list tables")
+		fmt.Fprintln(r.out, ".schema [table]          show CREATE statements")
+		fmt.Fprintln(r.out, ".mode table|csv|json     set output format")
+		fmt.Fprintln(r.out, ".output file|stdout      redirect output")
+		fmt.Fprintln(r.out, ".import file.csv table   import a file into table")
+		fmt.Fprintln(r.out, ".exit, .quit             leave the session")
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, try .help", cmd)
+	}
+}
+
+// printColumn runs query, which must select a single text column, and
+// prints one value per line, optionally appended with suffix.
+func (r *repl) printColumn(query string, suffix ...string) error {
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, v+strings.Join(suffix, ""))
+	}
+	return rows.Err()
+}
+
+// runQuery executes query and prints any result set in the current mode.
+func (r *repl) runQuery(query string) error {
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		return rows.Err()
+	}
+
+	var table [][]string
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		row := make([]string, len(cols))
+		for i, v := range vals {
+			row[i] = formatCell(v)
+		}
+		table = append(table, row)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	switch r.mode {
+	case outputCSV:
+		return r.printCSV(cols, table)
+	case outputJSON:
+		return r.printJSON(cols, table)
+	default:
+		return r.printTable(cols, table)
+	}
+}
+
+func formatCell(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(x)
+	default:
+		return fmt.Sprintf("%v", x)
+	}
+}
+
+func (r *repl) printCSV(cols []string, rows [][]string) error {
+	w := csv.NewWriter(r.out)
+	if err := w.Write(cols); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (r *repl) printJSON(cols []string, rows [][]string) error {
+	out := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		rec := make(map[string]string, len(cols))
+		for j, c := range cols {
+			rec[c] = row[j]
+		}
+		out[i] = rec
+	}
+
+	enc := json.NewEncoder(r.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func (r *repl) printTable(cols []string, rows [][]string) error {
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		widths[i] = len(c)
+	}
+	for _, row := range rows {
+		for i, v := range row {
+			if len(v) > widths[i] {
+				widths[i] = len(v)
+			}
+		}
+	}
+
+	printRow := func(row []string) {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = padRight(v, widths[i])
+		}
+		fmt.Fprintln(r.out, strings.Join(cells, "  "))
+	}
+
+	printRow(cols)
+
+	sep := make([]string, len(cols))
+	for i, w := range widths {
+		sep[i] = strings.Repeat("-", w)
+	}
+	fmt.Fprintln(r.out, strings.Join(sep, "  "))
+
+	for _, row := range rows {
+		printRow(row)
+	}
+
+	return nil
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// quoteSQLString quotes s as a single-quoted sqlite string literal.
+func quoteSQLString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
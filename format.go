@@ -0,0 +1,321 @@
+package csv2sqlite
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Format identifies the parser ImportFile should use for an input file.
+type Format string
+
+// Supported formats. The zero value means "detect from file extension".
+const (
+	FormatCSV   Format = "csv"
+	FormatTSV   Format = "tsv"
+	FormatJSONL Format = "jsonl"
+	FormatXLSX  Format = "xlsx"
+)
+
+// detectFormat guesses a Format from a (decompression-suffix-stripped) file
+// name, defaulting to FormatCSV.
+func detectFormat(name string) Format {
+	switch {
+	case strings.HasSuffix(name, ".tsv"):
+		return FormatTSV
+	case strings.HasSuffix(name, ".jsonl"), strings.HasSuffix(name, ".ndjson"):
+		return FormatJSONL
+	case strings.HasSuffix(name, ".xlsx"):
+		return FormatXLSX
+	default:
+		return FormatCSV
+	}
+}
+
+// sliceRowReader adapts an in-memory [][]string, such as the rows returned
+// by excelize, to the rowReader interface so it can share importRows with
+// the CSV/TSV path.
+type sliceRowReader struct {
+	rows [][]string
+	i    int
+}
+
+func (s *sliceRowReader) Read() ([]string, error) {
+	if s.i >= len(s.rows) {
+		return nil, io.EOF
+	}
+	row := s.rows[s.i]
+	s.i++
+	return row, nil
+}
+
+// importXLSX reads the first sheet of an Excel workbook and imports it
+// through the same pipeline used for CSV/TSV.
+func (imp *Importer) importXLSX(ctx context.Context, src io.Reader, bytesRead *int64) (Stats, error) {
+	xf, err := excelize.OpenReader(src)
+	if err != nil {
+		return Stats{}, fmt.Errorf("open xlsx err: %w", err)
+	}
+	defer xf.Close()
+
+	sheets := xf.GetSheetList()
+	if len(sheets) == 0 {
+		return Stats{}, fmt.Errorf("xlsx file has no sheets")
+	}
+
+	rows, err := xf.GetRows(sheets[0])
+	if err != nil {
+		return Stats{}, fmt.Errorf("read xlsx sheet %q err: %w", sheets[0], err)
+	}
+
+	return imp.importRows(ctx, &sliceRowReader{rows: rows}, bytesRead, "")
+}
+
+// flattenJSON flattens nested objects in m into a single-level map, joining
+// keys with "_". Arrays and other non-scalar values are left as-is for the
+// caller to encode.
+func flattenJSON(m map[string]interface{}, prefix string) map[string]interface{} {
+	flat := make(map[string]interface{})
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "_" + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			for nk, nv := range flattenJSON(nested, key) {
+				flat[nk] = nv
+			}
+			continue
+		}
+		flat[key] = v
+	}
+	return flat
+}
+
+// jsonValueType returns the sqlite type affinity for a decoded JSON value.
+func jsonValueType(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		return typeNumeric
+	case float64:
+		if val == math.Trunc(val) {
+			return typeInteger
+		}
+		return typeReal
+	case string:
+		if isoDateRegex.MatchString(val) {
+			return typeNumeric
+		}
+		return typeText
+	default:
+		return typeText
+	}
+}
+
+// jsonValueString renders a decoded JSON value as the string form expected
+// by the shared insert path, matching how classifyValue interprets CSV
+// fields. Non-scalar values (arrays, nested objects that survived
+// flattening under a map key collision) are re-encoded as JSON text.
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	case string:
+		return val
+	case float64:
+		return jsonNumberString(val)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(b)
+	}
+}
+
+func jsonNumberString(f float64) string {
+	if f == math.Trunc(f) {
+		return fmt.Sprintf("%d", int64(f))
+	}
+	return fmt.Sprintf("%g", f)
+}
+
+// importJSONL imports newline-delimited JSON objects, creating the table
+// from the first record's keys and adding columns dynamically as new keys
+// appear in later records. sourceName is used for reject-file naming.
+func (imp *Importer) importJSONL(ctx context.Context, src io.Reader, bytesRead *int64, sourceName string) (Stats, error) {
+	stats := Stats{TableName: imp.TableName}
+
+	var reject *rejectWriter
+	defer func() {
+		if reject != nil {
+			reject.Close()
+		}
+	}()
+	ensureReject := func() (*rejectWriter, error) {
+		if reject != nil {
+			return reject, nil
+		}
+		path := imp.rejectPath(sourceName)
+		if path == "" {
+			return nil, fmt.Errorf("--on-error=log requires a source filename or RejectPath")
+		}
+		rw, err := newRejectWriter(path)
+		if err != nil {
+			return nil, err
+		}
+		reject = rw
+		return reject, nil
+	}
+
+	if imp.Truncate && !imp.truncated {
+		if _, err := imp.DB.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", imp.TableName)); err != nil {
+			return stats, fmt.Errorf("drop table err: %w", err)
+		}
+		imp.truncated = true
+	}
+
+	if _, err := imp.DB.ExecContext(ctx, "PRAGMA journal_mode = WAL"); err != nil {
+		return stats, fmt.Errorf("PRAGMA journal_mode = WAL err: %w", err)
+	}
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var (
+		columns     []string // in discovery order
+		columnTypes = map[string]string{}
+		tableExists bool
+	)
+
+	ensureColumns := func(rec map[string]interface{}) error {
+		// Once the table exists, imp.CreateColumns=false means new keys are
+		// ignored rather than tracked: adding them to columns/columnTypes
+		// here but skipping the ALTER TABLE below would leave later INSERTs
+		// referencing columns the table doesn't have.
+		if tableExists && !imp.CreateColumns {
+			return nil
+		}
+
+		var newCols []string
+		for col := range rec {
+			if _, ok := columnTypes[col]; ok {
+				continue
+			}
+			newCols = append(newCols, col)
+		}
+		// rec is a map, so keys arrive in random order; sort them so the
+		// resulting CREATE TABLE/ALTER TABLE column order is reproducible
+		// across runs of the same input.
+		sort.Strings(newCols)
+		for _, col := range newCols {
+			columnTypes[col] = typeText // placeholder until we see a non-null value
+			columns = append(columns, col)
+		}
+		for _, col := range newCols {
+			if t := jsonValueType(rec[col]); t != "" {
+				columnTypes[col] = t
+			}
+		}
+
+		if !tableExists {
+			columnDefs := make([]string, len(columns))
+			for i, c := range columns {
+				columnDefs[i] = fmt.Sprintf("%s %s", c, columnTypes[c])
+			}
+			createStmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s)", imp.TableName, strings.Join(columnDefs, ",\n\t"))
+			if _, err := imp.DB.ExecContext(ctx, createStmt); err != nil {
+				return fmt.Errorf("create table err: %w (%s)", err, createStmt)
+			}
+			tableExists = true
+			return nil
+		}
+
+		for _, col := range newCols {
+			if _, err := imp.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", imp.TableName, col, columnTypes[col])); err != nil {
+				return fmt.Errorf("add column %s err: %w", col, err)
+			}
+			stats.ColumnsAdded = append(stats.ColumnsAdded, col)
+		}
+		return nil
+	}
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			cont, rerr := imp.handleReadError(err, lineNum, sourceName, line, ensureReject)
+			if !cont {
+				return stats, fmt.Errorf("parse json line err: %w", rerr)
+			}
+			continue
+		}
+
+		flat := flattenJSON(rec, "")
+		normalized := make(map[string]interface{}, len(flat))
+		for k, v := range flat {
+			k = strings.ToLower(strings.TrimSpace(k))
+			k = columnRegex.ReplaceAllString(k, "_")
+			normalized[k] = v
+		}
+
+		if err := ensureColumns(normalized); err != nil {
+			return stats, err
+		}
+
+		qs := strings.Repeat("?,", len(columns))
+		qs = qs[:len(qs)-1]
+		insertStmt := fmt.Sprintf("INSERT INTO %s (%s) values (%s)", imp.TableName, strings.Join(columns, ","), qs)
+
+		values := make([]interface{}, len(columns))
+		for i, col := range columns {
+			if v, ok := normalized[col]; ok {
+				values[i] = jsonValueString(v)
+			} else {
+				values[i] = nil
+			}
+		}
+
+		if _, err := imp.DB.ExecContext(ctx, insertStmt, values...); err != nil {
+			return stats, fmt.Errorf("insert err: %w", err)
+		}
+		stats.RowsInserted++
+
+		if bytesRead != nil {
+			stats.BytesProcessed = atomic.LoadInt64(bytesRead)
+		}
+		if imp.ProgressFn != nil {
+			imp.ProgressFn(stats)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("read err: %w", err)
+	}
+
+	return stats, nil
+}
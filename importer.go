@@ -0,0 +1,781 @@
+// Package csv2sqlite imports delimited text files into a sqlite database,
+// inferring column types from the data. It is the library underlying the
+// csv2sqlite command line tool and can be embedded directly by other Go
+// programs that want to load CSV data without shelling out.
+package csv2sqlite
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	_ "modernc.org/sqlite"
+)
+
+// defaultBatchSize is the number of rows committed per transaction when
+// Importer.BatchSize is unset.
+const defaultBatchSize = 10000
+
+// defaultFastCacheSize is the sqlite page cache size (in KB, negative per
+// sqlite's PRAGMA cache_size convention) used when Importer.Fast is set and
+// CacheSize is left at zero.
+const defaultFastCacheSize = -64000
+
+// sqlite type affinities we infer or accept via SchemaOverrides.
+const (
+	typeInteger = "INTEGER"
+	typeReal    = "REAL"
+	typeText    = "TEXT"
+	typeBlob    = "BLOB"
+	typeNumeric = "NUMERIC"
+)
+
+var validColumnTypes = map[string]string{
+	"integer": typeInteger,
+	"int":     typeInteger,
+	"real":    typeReal,
+	"float":   typeReal,
+	"double":  typeReal,
+	"text":    typeText,
+	"string":  typeText,
+	"blob":    typeBlob,
+	"numeric": typeNumeric,
+	"date":    typeNumeric,
+	"bool":    typeNumeric,
+	"boolean": typeNumeric,
+}
+
+var (
+	integerRegex = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	realRegex    = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)([eE][-+]?[0-9]+)?$`)
+	isoDateRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?)?$`)
+	booleanRegex = regexp.MustCompile(`^(?i:true|false)$`)
+	columnRegex  = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+)
+
+// Stats describes the outcome of an import.
+type Stats struct {
+	TableName      string
+	RowsInserted   int64
+	BytesProcessed int64
+	ColumnsAdded   []string
+}
+
+// Importer imports delimited text files into a sqlite table.
+type Importer struct {
+	// DB is the already-open destination database. Callers own its
+	// lifecycle (open/close).
+	DB *sql.DB
+
+	TableName     string
+	Separator     rune
+	Header        []string // explicit header; if nil/empty the first row of input is used
+	CreateColumns bool
+	Truncate      bool
+	BatchSize     int
+
+	// Fast enables PRAGMA toggles (synchronous=OFF, a larger cache_size)
+	// that trade crash-safety for bulk-load throughput.
+	Fast      bool
+	CacheSize int
+
+	TypeInference   bool
+	SampleRows      int
+	SchemaOverrides map[string]string
+
+	// Format forces the parser used for ImportFile. If empty, the format is
+	// detected from the (decompressed) file extension.
+	Format Format
+
+	// Encoding is the source text encoding: "" or "utf-8" (default), "gbk",
+	// "latin1", or "utf-16". It is ignored for FormatXLSX, which is binary.
+	Encoding string
+
+	// CSV reader leniency knobs; see encoding/csv.Reader for semantics.
+	LazyQuotes       bool
+	FieldsPerRecord  int  // 0 matches encoding/csv's default (first record's width); pass -1 to allow ragged rows
+	Comment          rune // lines starting with Comment are skipped; 0 disables
+	TrimLeadingSpace bool
+
+	// OnError controls how malformed rows are handled. The zero value
+	// behaves like OnErrorFail.
+	OnError OnErrorMode
+	// RejectPath overrides the sidecar file OnErrorLog writes rejected rows
+	// to. If empty, it defaults to "<input file>.rejects.csv"; ImportReader
+	// callers using OnErrorLog must set it explicitly since there is no
+	// input filename to derive it from.
+	RejectPath string
+
+	// ProgressFn, if set, is called as rows are imported.
+	ProgressFn func(Stats)
+
+	truncated bool
+}
+
+// OnErrorMode selects how ImportFile/ImportReader react to a malformed row.
+type OnErrorMode string
+
+const (
+	// OnErrorFail aborts the import on the first malformed row (default).
+	OnErrorFail OnErrorMode = "fail"
+	// OnErrorSkip silently drops malformed rows and continues.
+	OnErrorSkip OnErrorMode = "skip"
+	// OnErrorLog drops malformed rows and records them, with their source
+	// file and line number, to a reject sidecar file.
+	OnErrorLog OnErrorMode = "log"
+)
+
+// ParseSchemaOverrides parses a "col:type,col:type" string into a map of
+// column name to sqlite type affinity suitable for Importer.SchemaOverrides.
+func ParseSchemaOverrides(s string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	if s == "" {
+		return overrides, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid schema entry %q, expected col:type", pair)
+		}
+
+		col := strings.TrimSpace(parts[0])
+		typ, ok := validColumnTypes[strings.ToLower(strings.TrimSpace(parts[1]))]
+		if !ok {
+			return nil, fmt.Errorf("invalid schema entry %q, unknown type %q", pair, parts[1])
+		}
+
+		overrides[col] = typ
+	}
+
+	return overrides, nil
+}
+
+// decompressReader transparently decompresses r based on name, returning r
+// itself if no decompression is needed.
+func decompressReader(r io.Reader, name string) (io.Reader, error) {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return gzip.NewReader(r)
+	case strings.HasSuffix(name, ".bz2"):
+		return bzip2.NewReader(r), nil
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case strings.HasSuffix(name, ".xz"):
+		return xz.NewReader(r)
+	}
+
+	return r, nil
+}
+
+// trimCompressionSuffix strips a trailing compression extension (.gz, .bz2,
+// .zst, .xz) from name, so format detection can look at the extension
+// underneath, e.g. "sales.tsv.gz" -> "sales.tsv".
+func trimCompressionSuffix(name string) string {
+	for _, ext := range []string{".gz", ".bz2", ".zst", ".xz"} {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// countingReader wraps an io.Reader, invoking onRead with the number of
+// bytes returned by each successful Read call. It is used to report import
+// progress in terms of bytes consumed from the underlying file, as opposed
+// to bytes produced by any decompression layered on top of it.
+type countingReader struct {
+	r      io.Reader
+	onRead func(n int)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 && c.onRead != nil {
+		c.onRead(n)
+	}
+	return n, err
+}
+
+// classifyValue returns the sqlite type affinity that a single field value
+// is compatible with. Empty strings are treated as nullable and contribute
+// no type information.
+func classifyValue(v string) string {
+	if v == "" {
+		return ""
+	}
+	if integerRegex.MatchString(v) {
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return typeInteger
+		}
+		return typeReal
+	}
+	if realRegex.MatchString(v) {
+		return typeReal
+	}
+	if isoDateRegex.MatchString(v) || booleanRegex.MatchString(v) {
+		return typeNumeric
+	}
+	return typeText
+}
+
+// promoteType widens a column's running type to accommodate a newly seen
+// value's type, following INTEGER -> REAL -> TEXT. NUMERIC (dates/bools)
+// only combines with itself or an unset column; anything else widens to TEXT.
+func promoteType(current, next string) string {
+	if next == "" {
+		return current
+	}
+	if current == "" {
+		return next
+	}
+	if current == next {
+		return current
+	}
+
+	switch {
+	case current == typeInteger && next == typeReal, current == typeReal && next == typeInteger:
+		return typeReal
+	default:
+		return typeText
+	}
+}
+
+// isNumericType reports whether typ is a column affinity that should
+// coerce an empty CSV field to SQL NULL rather than the literal string "",
+// so blanks don't corrupt aggregates (AVG, SUM) or orderings.
+func isNumericType(typ string) bool {
+	switch typ {
+	case typeInteger, typeReal, typeNumeric:
+		return true
+	default:
+		return false
+	}
+}
+
+// inferColumnTypes scans sample rows and returns the inferred sqlite type
+// affinity for each column in header, falling back to TEXT for columns with
+// no non-empty values in the sample.
+func inferColumnTypes(header []string, rows [][]string) []string {
+	types := make([]string, len(header))
+
+	for _, row := range rows {
+		for i := range header {
+			if i >= len(row) {
+				continue
+			}
+			types[i] = promoteType(types[i], classifyValue(row[i]))
+		}
+	}
+
+	for i, t := range types {
+		if t == "" {
+			types[i] = typeText
+		}
+	}
+
+	return types
+}
+
+// ImportFile opens filename, transparently decompressing it based on its
+// extension, and imports it, dispatching to a parser based on imp.Format or,
+// if unset, the (decompressed) file extension. Unlike ImportReader, progress
+// reports include bytes read from the underlying file.
+func (imp *Importer) ImportFile(ctx context.Context, filename string) (Stats, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return Stats{}, fmt.Errorf("open file err: %w", err)
+	}
+	defer f.Close()
+
+	var bytesRead int64
+	cr := &countingReader{r: f, onRead: func(n int) {
+		atomic.AddInt64(&bytesRead, int64(n))
+	}}
+
+	dr, err := decompressReader(cr, filename)
+	if err != nil {
+		return Stats{}, fmt.Errorf("decompressReader err: %w", err)
+	}
+
+	format := imp.Format
+	if format == "" {
+		format = detectFormat(trimCompressionSuffix(filename))
+	}
+
+	if format != FormatXLSX {
+		dr, err = encodingReader(dr, imp.Encoding)
+		if err != nil {
+			return Stats{}, err
+		}
+	}
+
+	switch format {
+	case FormatJSONL:
+		return imp.importJSONL(ctx, dr, &bytesRead, filename)
+	case FormatXLSX:
+		return imp.importXLSX(ctx, dr, &bytesRead)
+	default:
+		r := imp.newCSVReader(dr, format)
+		return imp.importRows(ctx, r, &bytesRead, filename)
+	}
+}
+
+// ImportReader reads delimited records from src and inserts them into
+// imp.TableName, creating the table and any missing columns as needed. It
+// stops and returns an error if ctx is canceled. Only the CSV/TSV formats
+// are supported; use ImportFile for JSON Lines or Excel input.
+func (imp *Importer) ImportReader(ctx context.Context, src io.Reader) (Stats, error) {
+	dr, err := encodingReader(src, imp.Encoding)
+	if err != nil {
+		return Stats{}, err
+	}
+	r := imp.newCSVReader(dr, imp.Format)
+	return imp.importRows(ctx, r, nil, "")
+}
+
+// newCSVReader builds a csv.Reader configured from imp's leniency settings,
+// wrapped so the raw text of each record is available to reject logging.
+func (imp *Importer) newCSVReader(src io.Reader, format Format) *teeingCSVReader {
+	return newTeeingCSVReader(src, func(r *csv.Reader) {
+		r.Comma = imp.separatorFor(format)
+		r.LazyQuotes = imp.LazyQuotes
+		r.TrimLeadingSpace = imp.TrimLeadingSpace
+		r.Comment = imp.Comment
+		r.FieldsPerRecord = imp.FieldsPerRecord
+	})
+}
+
+// separatorFor returns imp.Separator if explicitly set, otherwise the
+// default for format (tab for TSV, comma otherwise).
+func (imp *Importer) separatorFor(format Format) rune {
+	if imp.Separator != 0 {
+		return imp.Separator
+	}
+	if format == FormatTSV {
+		return '\t'
+	}
+	return ','
+}
+
+// rowReader yields records one at a time, returning io.EOF once exhausted.
+// *csv.Reader satisfies this interface.
+type rowReader interface {
+	Read() ([]string, error)
+}
+
+// rawRowReader is implemented by a rowReader that can report the raw,
+// unparsed text of the most recently read (or failed) record, so a reject
+// log can show the offending line instead of leaving it blank.
+type rawRowReader interface {
+	rowReader
+	rawRecord() string
+}
+
+// teeingCSVReader wraps a *csv.Reader, capturing the raw bytes of each
+// record as it's consumed so a parse error can be logged alongside the
+// text that failed to parse, not just the error message. base tracks the
+// absolute stream offset of buf's first byte, since buf is drained after
+// every record to keep memory bounded regardless of file size.
+type teeingCSVReader struct {
+	r    *csv.Reader
+	buf  bytes.Buffer
+	base int64
+	last string
+}
+
+func newTeeingCSVReader(src io.Reader, configure func(*csv.Reader)) *teeingCSVReader {
+	t := &teeingCSVReader{}
+	t.r = csv.NewReader(io.TeeReader(src, &t.buf))
+	configure(t.r)
+	return t
+}
+
+func (t *teeingCSVReader) Read() ([]string, error) {
+	row, err := t.r.Read()
+	cur := t.r.InputOffset()
+	n := int(cur - t.base)
+	if n > t.buf.Len() {
+		n = t.buf.Len()
+	}
+	t.last = strings.TrimRight(string(t.buf.Next(n)), "\r\n")
+	t.base = cur
+	return row, err
+}
+
+func (t *teeingCSVReader) rawRecord() string { return t.last }
+
+// importRows drives the shared header/type-inference/batch-insert pipeline
+// used by the CSV, TSV, and Excel parsers. sourceName is the input filename
+// (used for reject-file naming and line numbers), or "" when imported via
+// ImportReader.
+func (imp *Importer) importRows(ctx context.Context, r rowReader, bytesRead *int64, sourceName string) (Stats, error) {
+	stats := Stats{TableName: imp.TableName}
+
+	var reject *rejectWriter
+	defer func() {
+		if reject != nil {
+			reject.Close()
+		}
+	}()
+	ensureReject := func() (*rejectWriter, error) {
+		if reject != nil {
+			return reject, nil
+		}
+		path := imp.rejectPath(sourceName)
+		if path == "" {
+			return nil, fmt.Errorf("--on-error=log requires a source filename or RejectPath")
+		}
+		rw, err := newRejectWriter(path)
+		if err != nil {
+			return nil, err
+		}
+		reject = rw
+		return reject, nil
+	}
+
+	if imp.Truncate && !imp.truncated {
+		if _, err := imp.DB.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", imp.TableName)); err != nil {
+			return stats, fmt.Errorf("drop table err: %w", err)
+		}
+		imp.truncated = true
+	}
+
+	if _, err := imp.DB.ExecContext(ctx, "PRAGMA journal_mode = WAL"); err != nil {
+		return stats, fmt.Errorf("PRAGMA journal_mode = WAL err: %w", err)
+	}
+
+	if imp.Fast {
+		if _, err := imp.DB.ExecContext(ctx, "PRAGMA synchronous = OFF"); err != nil {
+			return stats, fmt.Errorf("PRAGMA synchronous = OFF err: %w", err)
+		}
+		cacheSize := imp.CacheSize
+		if cacheSize == 0 {
+			cacheSize = defaultFastCacheSize
+		}
+		if _, err := imp.DB.ExecContext(ctx, fmt.Sprintf("PRAGMA cache_size = %d", cacheSize)); err != nil {
+			return stats, fmt.Errorf("PRAGMA cache_size err: %w", err)
+		}
+	}
+
+	// rowNum tracks the 1-based line within sourceName, so reject records
+	// line up with what a reader would count in the file itself. It starts
+	// at 1 when the header is read from the input (consuming its line
+	// before any data row), or 0 when imp.Header supplies the column names
+	// and every row is data.
+	rowNum := 0
+	header := imp.Header
+	var err error
+	if len(header) == 0 {
+		header, err = r.Read()
+		if err != nil {
+			return stats, fmt.Errorf("read header err: %w", err)
+		}
+		rowNum = 1
+	}
+	header = append([]string(nil), header...)
+
+	// readRow reads the next record, applying imp.OnError to any parse
+	// error instead of always aborting. It returns io.EOF at end of input.
+	readRow := func() ([]string, error) {
+		for {
+			line, err := r.Read()
+			rowNum++
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			if err != nil {
+				content := ""
+				if rr, ok := r.(rawRowReader); ok {
+					content = rr.rawRecord()
+				}
+				cont, rerr := imp.handleReadError(err, rowNum, sourceName, content, ensureReject)
+				if !cont {
+					return nil, rerr
+				}
+				continue
+			}
+			return line, nil
+		}
+	}
+
+	for i, h := range header {
+		h = strings.ToLower(h)
+		h = strings.TrimSpace(h)
+		h = columnRegex.ReplaceAllString(h, "_")
+		header[i] = h
+	}
+
+	sampleRows := imp.SampleRows
+	if !imp.TypeInference {
+		sampleRows = 0
+	}
+
+	var sampleBuf [][]string
+	for i := 0; i < sampleRows; i++ {
+		line, err := readRow()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, fmt.Errorf("read err: %w", err)
+		}
+		row := append([]string(nil), line...)
+		sampleBuf = append(sampleBuf, row)
+	}
+
+	var colTypes []string
+	if imp.TypeInference {
+		colTypes = inferColumnTypes(header, sampleBuf)
+	} else {
+		colTypes = make([]string, len(header))
+		for i := range colTypes {
+			colTypes[i] = typeText
+		}
+	}
+	for col, typ := range imp.SchemaOverrides {
+		for i, h := range header {
+			if h == col {
+				colTypes[i] = typ
+			}
+		}
+	}
+
+	columnDefs := make([]string, len(header))
+	for i, h := range header {
+		columnDefs[i] = fmt.Sprintf("%s %s", h, colTypes[i])
+	}
+
+	createStmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s)", imp.TableName, strings.Join(columnDefs, ",\n\t"))
+	if _, err := imp.DB.ExecContext(ctx, createStmt); err != nil {
+		return stats, fmt.Errorf("create table err: %w (%s)", err, createStmt)
+	}
+
+	if imp.CreateColumns {
+		added, err := imp.addMissingColumns(ctx, header, colTypes)
+		if err != nil {
+			return stats, err
+		}
+		stats.ColumnsAdded = added
+	}
+
+	qs := strings.Repeat("?,", len(header))
+	qs = qs[:len(qs)-1]
+	insertStmt := fmt.Sprintf("INSERT INTO %s (%s) values (%s)", imp.TableName, strings.Join(header, ","), qs)
+
+	batchSize := imp.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var (
+		tx   *sql.Tx
+		stmt *sql.Stmt
+	)
+	beginBatch := func() error {
+		var err error
+		tx, err = imp.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		stmt, err = tx.PrepareContext(ctx, insertStmt)
+		if err != nil {
+			return fmt.Errorf("prepare insert err: %w", err)
+		}
+		return nil
+	}
+	commitBatch := func() error {
+		if err := stmt.Close(); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if err := beginBatch(); err != nil {
+		return stats, err
+	}
+
+	rowFace := make([]interface{}, len(header))
+	rowsInBatch := 0
+	insertRow := func(line []string) error {
+		// line may be shorter or longer than header: --fields-per-record=-1
+		// (the lenient default) lets ragged rows reach here. Pad missing
+		// trailing fields with NULL and drop any extras rather than
+		// indexing out of range or leaving stale values from a reused
+		// rowFace slot.
+		n := len(line)
+		if n > len(rowFace) {
+			n = len(rowFace)
+		}
+		for i := 0; i < n; i++ {
+			// An empty field in a numeric column must become SQL NULL, not
+			// the literal string "", or it coerces to 0 in aggregates and
+			// sorts as a real value instead of last.
+			if line[i] == "" && isNumericType(colTypes[i]) {
+				rowFace[i] = nil
+				continue
+			}
+			rowFace[i] = line[i]
+		}
+		for i := n; i < len(rowFace); i++ {
+			rowFace[i] = nil
+		}
+
+		if _, err := stmt.ExecContext(ctx, rowFace...); err != nil {
+			return err
+		}
+		stats.RowsInserted++
+		rowsInBatch++
+
+		if rowsInBatch >= batchSize {
+			if err := commitBatch(); err != nil {
+				return err
+			}
+			if err := beginBatch(); err != nil {
+				return err
+			}
+			rowsInBatch = 0
+		}
+
+		if bytesRead != nil {
+			stats.BytesProcessed = atomic.LoadInt64(bytesRead)
+		}
+		if imp.ProgressFn != nil {
+			imp.ProgressFn(stats)
+		}
+		return nil
+	}
+
+	for _, line := range sampleBuf {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if err := insertRow(line); err != nil {
+			return stats, err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		line, err := readRow()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, fmt.Errorf("read err: %w", err)
+		}
+
+		if err := insertRow(line); err != nil {
+			return stats, err
+		}
+	}
+
+	if err := commitBatch(); err != nil {
+		return stats, err
+	}
+
+	return stats, nil
+}
+
+// handleReadError applies imp.OnError to a row read error, returning
+// whether importRows should skip the row and continue. content is the raw
+// offending row text when the caller has it (e.g. a JSONL line); it is
+// recorded alongside the error for OnErrorLog, and left empty when a row
+// failed to parse into text at all (e.g. a malformed CSV record).
+func (imp *Importer) handleReadError(err error, line int, sourceName, content string, ensureReject func() (*rejectWriter, error)) (bool, error) {
+	switch imp.OnError {
+	case OnErrorSkip:
+		return true, nil
+	case OnErrorLog:
+		rw, rerr := ensureReject()
+		if rerr != nil {
+			return false, rerr
+		}
+		if werr := rw.reject(sourceName, line, content, err); werr != nil {
+			return false, werr
+		}
+		return true, nil
+	default: // OnErrorFail, ""
+		return false, err
+	}
+}
+
+// rejectPath returns the sidecar file OnErrorLog should write to.
+func (imp *Importer) rejectPath(sourceName string) string {
+	if imp.RejectPath != "" {
+		return imp.RejectPath
+	}
+	if sourceName != "" {
+		return sourceName + ".rejects.csv"
+	}
+	return ""
+}
+
+// addMissingColumns adds any columns in header that are not already present
+// in imp.TableName, returning the names of the columns that were added.
+func (imp *Importer) addMissingColumns(ctx context.Context, header, colTypes []string) ([]string, error) {
+	missingHeaders := make(map[string]struct{}, len(header))
+	for _, h := range header {
+		missingHeaders[h] = struct{}{}
+	}
+
+	rows, err := imp.DB.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", imp.TableName))
+	if err != nil {
+		return nil, fmt.Errorf("query table_info err: %w", err)
+	}
+
+	for rows.Next() {
+		var (
+			cid       interface{}
+			name      string
+			colType   string
+			notnull   interface{}
+			dfltValue interface{}
+			pk        interface{}
+		)
+
+		if err := rows.Scan(&cid, &name, &colType, &notnull, &dfltValue, &pk); err != nil {
+			return nil, fmt.Errorf("scan table_info err: %w", err)
+		}
+
+		delete(missingHeaders, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("query table_info err: %w", err)
+	}
+
+	var added []string
+	for i, h := range header {
+		if _, ok := missingHeaders[h]; !ok {
+			continue
+		}
+		if _, err := imp.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", imp.TableName, h, colTypes[i])); err != nil {
+			return nil, fmt.Errorf("add column %s err: %w", h, err)
+		}
+		added = append(added, h)
+	}
+
+	return added, nil
+}
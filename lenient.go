@@ -0,0 +1,73 @@
+package csv2sqlite
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// encodingReader wraps r to transcode it from enc to UTF-8. An empty enc (or
+// "utf-8"/"utf8") returns r unchanged.
+func encodingReader(r io.Reader, enc string) (io.Reader, error) {
+	var e encoding.Encoding
+	switch strings.ToLower(enc) {
+	case "", "utf-8", "utf8":
+		return r, nil
+	case "gbk":
+		e = simplifiedchinese.GBK
+	case "latin1", "iso-8859-1":
+		e = charmap.ISO8859_1
+	case "utf-16", "utf16":
+		e = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", enc)
+	}
+	return transform.NewReader(r, e.NewDecoder()), nil
+}
+
+// rejectWriter records rows rejected by OnErrorLog to a sidecar CSV file.
+type rejectWriter struct {
+	f *os.File
+	w *csv.Writer
+}
+
+func newRejectWriter(path string) (*rejectWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create reject file err: %w", err)
+	}
+
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"file", "line", "row", "error"}); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write reject header err: %w", err)
+	}
+
+	return &rejectWriter{f: f, w: w}, nil
+}
+
+func (rw *rejectWriter) reject(sourceName string, line int, content string, cause error) error {
+	if err := rw.w.Write([]string{sourceName, strconv.Itoa(line), content, cause.Error()}); err != nil {
+		return fmt.Errorf("write reject row err: %w", err)
+	}
+	rw.w.Flush()
+	return rw.w.Error()
+}
+
+func (rw *rejectWriter) Close() error {
+	rw.w.Flush()
+	if err := rw.w.Error(); err != nil {
+		rw.f.Close()
+		return err
+	}
+	return rw.f.Close()
+}
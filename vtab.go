@@ -0,0 +1,458 @@
+package csv2sqlite
+
+// vtab.go implements --virtual mode: a SQLite virtual table module, backed
+// directly by a CSV file on disk, so a file can be queried with plain SQL
+// without an import step. It follows the yacr csvTabCursor pattern: Filter()
+// reseeks the underlying file to offsetFirstRow (just past the header) and
+// Next() scans forward from there, splitting one line per row.
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"modernc.org/sqlite/vtab"
+)
+
+// VirtualModuleName is the module name used in CREATE VIRTUAL TABLE
+// statements, e.g. CREATE VIRTUAL TABLE sales USING csv2sqlite(filename='sales.csv').
+const VirtualModuleName = "csv2sqlite"
+
+var registerVirtualModuleOnce sync.Once
+
+// RegisterVirtualModule registers the csv2sqlite virtual table module with
+// modernc.org/sqlite. It is safe to call more than once; only the first call
+// has an effect. Callers must invoke it before issuing a CREATE VIRTUAL
+// TABLE ... USING csv2sqlite(...) statement.
+func RegisterVirtualModule(db *sql.DB) {
+	registerVirtualModuleOnce.Do(func() {
+		vtab.RegisterModule(db, VirtualModuleName, &vtabModule{})
+	})
+}
+
+// maxIndexedFileSize caps how large a file can be before we build an
+// in-memory offset index for equality lookups on the first column. Above
+// this size we fall back to a linear scan with client-side filtering, since
+// building the index requires reading the whole file once up front -
+// exactly the cost --virtual is meant to avoid for multi-GB files.
+const maxIndexedFileSize = 256 << 20 // 256MB
+
+// vtabModule implements modernc.org/sqlite/vtab's Module interface.
+type vtabModule struct{}
+
+func (m *vtabModule) Create(ctx vtab.Context, args []string) (vtab.Table, error) {
+	return m.connect(ctx, args)
+}
+
+func (m *vtabModule) Connect(ctx vtab.Context, args []string) (vtab.Table, error) {
+	return m.connect(ctx, args)
+}
+
+// connect parses the module arguments (the db name, schema, and table name
+// sqlite supplies positionally, followed by key=value pairs from the USING
+// clause), reads the CSV header to determine columns, and declares the
+// table's schema.
+func (m *vtabModule) connect(ctx vtab.Context, args []string) (vtab.Table, error) {
+	opts, err := parseVTabArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if opts.filename == "" {
+		return nil, fmt.Errorf("csv2sqlite virtual table requires filename=...")
+	}
+
+	typeOverrides, err := ParseSchemaOverrides(opts.types)
+	if err != nil {
+		return nil, fmt.Errorf("csv2sqlite virtual table types err: %w", err)
+	}
+
+	t := &vtabTable{
+		path:      opts.filename,
+		separator: opts.separator,
+	}
+	if err := t.readHeader(); err != nil {
+		return nil, err
+	}
+
+	// Every column defaults to TEXT affinity, since declaring a type without
+	// scanning the whole file would be a guess; pass types=col:type (same
+	// syntax as --schema) to compare numerically instead of lexically.
+	columnDefs := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		typ, ok := typeOverrides[col]
+		if !ok {
+			typ = typeText
+		}
+		if i == 0 {
+			t.firstColType = typ
+		}
+		columnDefs[i] = fmt.Sprintf("%s %s", col, typ)
+	}
+	ddl := fmt.Sprintf("CREATE TABLE x(%s)", strings.Join(columnDefs, ", "))
+	if err := ctx.Declare(ddl); err != nil {
+		return nil, fmt.Errorf("declare vtab err: %w", err)
+	}
+
+	if t.size() <= maxIndexedFileSize {
+		if err := t.buildOffsetIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+type vtabArgs struct {
+	filename  string
+	separator rune
+	types     string // col:type,col:type overrides, same syntax as --schema
+}
+
+// parseVTabArgs reads the key=value pairs following sqlite's three
+// positional module arguments (database, schema, table name).
+func parseVTabArgs(args []string) (vtabArgs, error) {
+	opts := vtabArgs{separator: ','}
+	if len(args) <= 3 {
+		return opts, nil
+	}
+	for _, raw := range args[3:] {
+		kv := strings.SplitN(strings.TrimSpace(raw), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		val := strings.Trim(strings.TrimSpace(kv[1]), `'"`)
+		switch key {
+		case "filename":
+			opts.filename = val
+		case "separator":
+			if len(val) != 1 {
+				return opts, fmt.Errorf("csv2sqlite virtual table separator must be a single character")
+			}
+			opts.separator = rune(val[0])
+		case "types":
+			opts.types = val
+		}
+	}
+	return opts, nil
+}
+
+// vtabTable is a SQLite virtual table backed by a CSV file.
+type vtabTable struct {
+	path         string
+	separator    rune
+	columns      []string
+	firstColType string // declared affinity of columns[0]; governs index key normalization
+
+	offsetFirstRow int64 // byte offset of the first data row, past the header
+
+	indexMu sync.Mutex
+	index   map[string][]int64 // normalized first-column value -> byte offsets (possibly several), nil if not built
+}
+
+// normalizeIndexKey canonicalizes a first-column value for use as an index
+// key, so an equality filter matches regardless of whether the value came
+// from the raw CSV text (e.g. "05") or a query literal (e.g. the integer 5).
+// affinity is the column's declared type; raw is left as-is for TEXT columns
+// or values that don't parse as that affinity expects.
+func normalizeIndexKey(affinity, raw string) string {
+	switch affinity {
+	case typeInteger:
+		if n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64); err == nil {
+			return strconv.FormatInt(n, 10)
+		}
+	case typeReal, typeNumeric:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	}
+	return raw
+}
+
+func (t *vtabTable) size() int64 {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return maxIndexedFileSize + 1 // treat unreadable files as too large to index
+	}
+	return fi.Size()
+}
+
+// readHeader opens the file, reads the header row, and records
+// offsetFirstRow so Filter can reseek past it without re-parsing.
+func (t *vtabTable) readHeader() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("open %s err: %w", t.path, err)
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	r := csv.NewReader(br)
+	r.Comma = t.separator
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("read header from %s err: %w", t.path, err)
+	}
+	t.columns = append([]string(nil), header...)
+	for i, c := range t.columns {
+		c = strings.ToLower(strings.TrimSpace(c))
+		c = columnRegex.ReplaceAllString(c, "_")
+		if c == "" {
+			c = fmt.Sprintf("col%d", i+1)
+		}
+		t.columns[i] = c
+	}
+
+	// csv.Reader buffers ahead of what it has returned, so the byte count
+	// from our bufio.Reader overshoots the header line itself. Re-derive
+	// offsetFirstRow by scanning the raw file for the first newline instead.
+	t.offsetFirstRow, err = firstLineEnd(t.path)
+	return err
+}
+
+// firstLineEnd returns the byte offset just past the first line of path.
+func firstLineEnd(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var n int64
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return n, nil
+		}
+		n++
+		if b == '\n' {
+			return n, nil
+		}
+	}
+}
+
+// buildOffsetIndex scans the file once, recording the byte offset of every
+// row keyed by its first column's value, so equality filters on column 0
+// can seek directly to each matching row instead of scanning. The first
+// column is read through a real csv.Reader (not a raw split on separator)
+// so a quoted field containing the separator or an embedded newline still
+// keys correctly; r.InputOffset() gives the exact byte offset of each
+// record regardless of how csv.Reader buffers its input.
+func (t *vtabTable) buildOffsetIndex() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(t.offsetFirstRow, io.SeekStart); err != nil {
+		return err
+	}
+
+	r := csv.NewReader(f)
+	r.Comma = t.separator
+	r.FieldsPerRecord = -1
+
+	index := make(map[string][]int64)
+	offset := t.offsetFirstRow
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("index %s err: %w", t.path, err)
+		}
+		if len(row) > 0 {
+			key := normalizeIndexKey(t.firstColType, row[0])
+			index[key] = append(index[key], offset)
+		}
+		offset = t.offsetFirstRow + r.InputOffset()
+	}
+
+	t.indexMu.Lock()
+	t.index = index
+	t.indexMu.Unlock()
+	return nil
+}
+
+// indexConstraint identifies a usable sqlite index constraint: equality on
+// column 0.
+const indexConstraintFirstColEq = 1
+
+func (t *vtabTable) BestIndex(info *vtab.IndexInfo) error {
+	for i, cst := range info.Constraints {
+		if !cst.Usable || cst.Column != 0 || cst.Op != vtab.OpEQ {
+			continue
+		}
+		t.indexMu.Lock()
+		hasIndex := t.index != nil
+		t.indexMu.Unlock()
+		if !hasIndex {
+			continue
+		}
+		info.Constraints[i].ArgIndex = 0
+		info.Constraints[i].Omit = true
+		info.IdxNum = indexConstraintFirstColEq
+		info.EstimatedCost = 1
+		info.EstimatedRows = 1
+		return nil
+	}
+	info.IdxNum = 0
+	info.EstimatedCost = 1e6
+	return nil
+}
+
+func (t *vtabTable) Open() (vtab.Cursor, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s err: %w", t.path, err)
+	}
+	return &vtabCursor{t: t, f: f}, nil
+}
+
+func (t *vtabTable) Disconnect() error { return nil }
+func (t *vtabTable) Destroy() error    { return nil }
+
+// vtabCursor iterates rows of a single query over a vtabTable. When indexed
+// is true, it steps through a fixed list of byte offsets (one seek-and-read
+// per match) rather than scanning the file contiguously with c.r.
+type vtabCursor struct {
+	t   *vtabTable
+	f   *os.File
+	r   *csv.Reader
+	row []string
+	eof bool
+	n   int64 // rowid, counted from 1
+
+	indexed  bool
+	matches  []int64 // offsets of rows matching the equality filter, in file order
+	matchPos int
+}
+
+func (c *vtabCursor) Filter(idxNum int, idxStr string, vals []vtab.Value) error {
+	c.eof = false
+	c.n = 0
+	c.indexed = int64(idxNum) == indexConstraintFirstColEq && len(vals) == 1
+
+	if c.indexed {
+		key := normalizeIndexKey(c.t.firstColType, fmt.Sprintf("%v", vals[0]))
+		c.t.indexMu.Lock()
+		c.matches = c.t.index[key]
+		c.t.indexMu.Unlock()
+		c.matchPos = 0
+		return c.advanceIndexed()
+	}
+
+	if _, err := c.f.Seek(c.t.offsetFirstRow, io.SeekStart); err != nil {
+		return fmt.Errorf("seek %s err: %w", c.t.path, err)
+	}
+	c.r = csv.NewReader(bufio.NewReader(c.f))
+	c.r.Comma = c.t.separator
+	c.r.FieldsPerRecord = -1
+
+	return c.scanNext()
+}
+
+func (c *vtabCursor) Next() error {
+	if c.eof {
+		return nil
+	}
+	if c.indexed {
+		return c.advanceIndexed()
+	}
+	return c.scanNext()
+}
+
+// scanNext reads the next record from the contiguous scan started by
+// Filter.
+func (c *vtabCursor) scanNext() error {
+	row, err := c.r.Read()
+	if err == io.EOF {
+		c.eof = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s err: %w", c.t.path, err)
+	}
+	c.row = row
+	c.n++
+	return nil
+}
+
+// advanceIndexed seeks to and reads the next of c.matches, the exact set of
+// rows the equality index found for this key. It reports EOF once every
+// match has been returned, which is what lets BestIndex set Omit on the
+// constraint without sqlite re-checking it against skipped rows.
+func (c *vtabCursor) advanceIndexed() error {
+	if c.matchPos >= len(c.matches) {
+		c.eof = true
+		return nil
+	}
+	offset := c.matches[c.matchPos]
+	c.matchPos++
+
+	if _, err := c.f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seek %s err: %w", c.t.path, err)
+	}
+	r := csv.NewReader(bufio.NewReader(c.f))
+	r.Comma = c.t.separator
+	r.FieldsPerRecord = -1
+
+	row, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("read %s err: %w", c.t.path, err)
+	}
+	c.row = row
+	c.n++
+	return nil
+}
+
+func (c *vtabCursor) Eof() bool { return c.eof }
+
+func (c *vtabCursor) Column(col int) (vtab.Value, error) {
+	if col < 0 || col >= len(c.row) {
+		return nil, nil
+	}
+	return c.row[col], nil
+}
+
+func (c *vtabCursor) Rowid() (int64, error) { return c.n, nil }
+
+func (c *vtabCursor) Close() error {
+	return c.f.Close()
+}
+
+var (
+	_ vtab.Table  = (*vtabTable)(nil)
+	_ vtab.Cursor = (*vtabCursor)(nil)
+)
+
+// quoteVTabString quotes s for interpolation into a CREATE VIRTUAL TABLE
+// module-argument list.
+func quoteVTabString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// CreateVirtualTable issues a CREATE VIRTUAL TABLE statement binding
+// tableName to path through the csv2sqlite module, so it can be queried
+// directly without an import step. types is a "col:type,col:type" string
+// in the same syntax as --schema, or empty to leave every column TEXT.
+// RegisterVirtualModule must have been called first.
+func CreateVirtualTable(db *sql.DB, tableName, path string, separator rune, types string) error {
+	stmt := fmt.Sprintf(
+		"CREATE VIRTUAL TABLE %s USING %s(filename=%s, separator=%s, types=%s)",
+		tableName, VirtualModuleName, quoteVTabString(path), quoteVTabString(string(separator)), quoteVTabString(types),
+	)
+	_, err := db.Exec(stmt)
+	return err
+}